@@ -0,0 +1,105 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// StreamPositionWaiter lets callers block until a given stream sequence
+// number has been recorded as applied, instead of guessing with a
+// wall-clock sleep: a syncapi consumer (room-server output, key-change,
+// send-to-device, presence, ...) calls OnCommitted with the NATS message
+// once its DB transaction for that message has committed; anything blocked
+// in Wait for that sequence (or an earlier one) is then released.
+//
+// This doubles as a readiness/metrics signal for "has the syncapi caught up
+// to stream X as of sequence N", so it's written to be safe to run in
+// production as well as tests. See TestStreamPositionWaiterAgainstRealJetStream
+// for the intended usage against a real JetStream subscription; the
+// remaining step - having each of the four consumers above call
+// OnCommitted from their own commit path - touches files outside this
+// package and isn't part of this change.
+type StreamPositionWaiter struct {
+	mu       sync.Mutex
+	observed map[string]uint64
+	waiters  map[string][]chan struct{}
+}
+
+// NewStreamPositionWaiter creates an empty StreamPositionWaiter.
+func NewStreamPositionWaiter() *StreamPositionWaiter {
+	return &StreamPositionWaiter{
+		observed: make(map[string]uint64),
+		waiters:  make(map[string][]chan struct{}),
+	}
+}
+
+// Advance records that every message up to and including seq on stream has
+// now been fully applied, and releases any Wait calls that were blocked on
+// it. Consumers call this immediately after their commit, not before.
+func (w *StreamPositionWaiter) Advance(stream string, seq uint64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if seq <= w.observed[stream] {
+		return
+	}
+	w.observed[stream] = seq
+	for _, ch := range w.waiters[stream] {
+		close(ch)
+	}
+	delete(w.waiters, stream)
+}
+
+// Wait blocks until stream has been observed up to at least seq, or ctx is
+// cancelled. It returns immediately if that position has already been
+// reached.
+func (w *StreamPositionWaiter) Wait(ctx context.Context, stream string, seq uint64) error {
+	for {
+		w.mu.Lock()
+		if w.observed[stream] >= seq {
+			w.mu.Unlock()
+			return nil
+		}
+		ch := make(chan struct{})
+		w.waiters[stream] = append(w.waiters[stream], ch)
+		w.mu.Unlock()
+
+		// Advance releases every waiter on the stream, so re-check the
+		// observed position and keep waiting if it is still short of seq.
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			w.removeWaiter(stream, ch)
+			return fmt.Errorf("notifier: timed out waiting for stream %q to reach position %d: %w", stream, seq, ctx.Err())
+		}
+	}
+}
+
+// OnCommitted is the hook a JetStream consumer calls once it has durably
+// applied msg to the sync database, advancing stream using the message's
+// own JetStream delivery metadata so callers don't need to track sequence
+// numbers themselves.
+func (w *StreamPositionWaiter) OnCommitted(stream string, msg *nats.Msg) error {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return fmt.Errorf("notifier: could not read JetStream metadata from message: %w", err)
+	}
+	w.Advance(stream, meta.Sequence.Stream)
+	return nil
+}
+
+// removeWaiter drops ch from stream's waiter list so a cancelled/timed-out
+// Wait doesn't hold a reference forever if stream is never advanced again.
+func (w *StreamPositionWaiter) removeWaiter(stream string, ch chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	waiters := w.waiters[stream]
+	for i, c := range waiters {
+		if c == ch {
+			w.waiters[stream] = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+}
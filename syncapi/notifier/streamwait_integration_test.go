@@ -0,0 +1,61 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matrix-org/dendrite/clientapi/producers"
+	"github.com/matrix-org/dendrite/setup/jetstream"
+	"github.com/matrix-org/dendrite/test"
+	"github.com/matrix-org/dendrite/test/testrig"
+	"github.com/nats-io/nats.go"
+)
+
+// TestStreamPositionWaiterAgainstRealJetStream exercises OnCommitted/Wait
+// against a real JetStream subscription rather than synthetic Advance/Wait
+// calls, demonstrating the wiring a consumer's commit path needs to add:
+// subscribe, process, call OnCommitted, ack. This is the integration point
+// the four syncapi consumers (room-server output, key-change,
+// send-to-device, presence) still need to adopt individually; those
+// consumers live outside this package.
+func TestStreamPositionWaiterAgainstRealJetStream(t *testing.T) {
+	test.WithAllDatabases(t, testStreamPositionWaiterAgainstRealJetStream)
+}
+
+func testStreamPositionWaiterAgainstRealJetStream(t *testing.T, dbType test.DBType) {
+	base, close := testrig.CreateBaseDendrite(t, dbType)
+	defer close()
+
+	jsctx, _ := base.NATS.Prepare(base.ProcessContext, &base.Cfg.Global.JetStream)
+	defer jetstream.DeleteAllStreams(jsctx, &base.Cfg.Global.JetStream)
+
+	const streamName = "send_to_device_test_stream"
+	waiter := NewStreamPositionWaiter()
+	subject := base.Cfg.Global.JetStream.Prefixed(jetstream.OutputSendToDeviceEvent)
+
+	sub, err := jsctx.Subscribe(subject, func(msg *nats.Msg) {
+		if err := waiter.OnCommitted(streamName, msg); err != nil {
+			t.Errorf("OnCommitted: %s", err)
+		}
+		_ = msg.Ack()
+	})
+	if err != nil {
+		t.Fatalf("failed to subscribe to %s: %s", subject, err)
+	}
+	defer func() { _ = sub.Unsubscribe() }()
+
+	producer := producers.SyncAPIProducer{
+		TopicSendToDeviceEvent: subject,
+		JetStream:              jsctx,
+	}
+	if err := producer.SendToDevice(context.Background(), "@alice:test", "@alice:test", "DEVICE", "m.dendrite.test", map[string]string{"dummy": "hello"}); err != nil {
+		t.Fatalf("failed to send to-device message: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := waiter.Wait(ctx, streamName, 1); err != nil {
+		t.Fatalf("Wait did not observe the published message in time: %s", err)
+	}
+}
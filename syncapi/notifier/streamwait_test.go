@@ -0,0 +1,77 @@
+package notifier
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStreamPositionWaiterUnblocksOnAdvance(t *testing.T) {
+	w := NewStreamPositionWaiter()
+
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		done <- w.Wait(ctx, "roomserver", 5)
+	}()
+
+	// Advancing to an earlier sequence must not release the waiter.
+	w.Advance("roomserver", 3)
+	select {
+	case err := <-done:
+		t.Fatalf("Wait returned early (err=%v) after an insufficient Advance", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	w.Advance("roomserver", 5)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Wait returned error: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not unblock after Advance reached the requested position")
+	}
+}
+
+func TestStreamPositionWaiterReturnsImmediatelyIfAlreadyObserved(t *testing.T) {
+	w := NewStreamPositionWaiter()
+	w.Advance("roomserver", 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := w.Wait(ctx, "roomserver", 7); err != nil {
+		t.Fatalf("expected Wait for an already-passed position to return immediately, got: %v", err)
+	}
+}
+
+func TestStreamPositionWaiterTimesOut(t *testing.T) {
+	w := NewStreamPositionWaiter()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := w.Wait(ctx, "roomserver", 1); err == nil {
+		t.Fatal("expected Wait to time out when the position is never reached")
+	}
+}
+
+func TestStreamPositionWaiterManyWaitersOneAdvance(t *testing.T) {
+	w := NewStreamPositionWaiter()
+	const n = 20
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if err := w.Wait(ctx, "keychange", 1); err != nil {
+				t.Errorf("Wait returned error: %v", err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond) // give the goroutines a chance to start waiting
+	w.Advance("keychange", 1)
+	wg.Wait()
+}
@@ -0,0 +1,141 @@
+// Package slidingsync implements the room-list windowing and diffing logic
+// behind the MSC3575 sliding sync endpoint. It is deliberately storage- and
+// transport-agnostic: it is handed a pre-computed room list by the caller
+// (the sliding sync HTTP handler's roomProvider) and deals with sorting,
+// filtering and diffing that list against what a connection last saw, and
+// with shaping each returned room's required_state, timeline and heroes.
+package slidingsync
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// ConnKey identifies one sliding sync connection. Unlike the opaque
+// StreamingToken used by v3 sync, a connection's `pos` is a short,
+// server-chosen string that indexes into in-memory ConnState kept here.
+type ConnKey struct {
+	DeviceID string
+	ConnID   string
+}
+
+// ConnState is everything the server remembers about a connection's last
+// computed view, so that the next request only needs to send the delta.
+type ConnState struct {
+	// Pos is the token that must be presented to resume from this state.
+	Pos string
+	// Windows holds, per list key, the ordered room IDs the client was last
+	// told occupy its requested ranges.
+	Windows map[string][]string
+	// Known is the set of rooms returned, via any list window or room
+	// subscription, in the response this state was computed for. The next
+	// response marks every room not in it as initial; required_state and
+	// timeline are sent in full for every returned room either way.
+	Known map[string]bool
+}
+
+// ConnMap tracks the most recent ConnState per connection, evicting the
+// least-recently-used connections once maxConns is exceeded. A connection
+// that falls out of the map simply causes its next request's `pos` to be
+// unrecognised, forcing a full re-sync — which is safe, if wasteful.
+type ConnMap struct {
+	mu       sync.Mutex
+	maxConns int
+	entries  map[ConnKey]*list.Element // ConnKey -> element in lru holding *connEntry
+	byPos    map[string]*list.Element  // pos -> same element, for O(1) lookup on request
+	lru      *list.List
+}
+
+type connEntry struct {
+	key   ConnKey
+	state ConnState
+}
+
+// NewConnMap creates a connection-state cache that keeps at most maxConns
+// connections resident, evicting the least-recently-used once that's
+// exceeded.
+func NewConnMap(maxConns int) *ConnMap {
+	return &ConnMap{
+		maxConns: maxConns,
+		entries:  make(map[ConnKey]*list.Element),
+		byPos:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Get returns the last known state for (deviceID, connID), if any is still
+// resident. ok is false if the connection is new or was evicted, in which
+// case callers must treat the request as an initial sync.
+func (m *ConnMap) Get(key ConnKey) (state ConnState, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, exists := m.entries[key]
+	if !exists {
+		return ConnState{}, false
+	}
+	m.lru.MoveToFront(el)
+	return el.Value.(*connEntry).state, true
+}
+
+// GetByPos resolves a `pos` token presented by the client back to the
+// connection state it was issued for. ok is false for an unknown or
+// evicted pos, which must trigger a full re-sync per MSC3575.
+func (m *ConnMap) GetByPos(pos string) (key ConnKey, state ConnState, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, exists := m.byPos[pos]
+	if !exists {
+		return ConnKey{}, ConnState{}, false
+	}
+	m.lru.MoveToFront(el)
+	entry := el.Value.(*connEntry)
+	return entry.key, entry.state, true
+}
+
+// Put stores the state computed for this response, issuing it a fresh pos
+// token and evicting the oldest connection if the cache is now over
+// capacity.
+func (m *ConnMap) Put(key ConnKey, state ConnState) (pos string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pos = newPos()
+	state.Pos = pos
+
+	if el, exists := m.entries[key]; exists {
+		// Replacing a connection's state invalidates its old pos: it must
+		// no longer resolve via GetByPos.
+		old := el.Value.(*connEntry)
+		delete(m.byPos, old.state.Pos)
+		el.Value = &connEntry{key: key, state: state}
+		m.lru.MoveToFront(el)
+		m.byPos[pos] = el
+		return pos
+	}
+
+	el := m.lru.PushFront(&connEntry{key: key, state: state})
+	m.entries[key] = el
+	m.byPos[pos] = el
+
+	for m.lru.Len() > m.maxConns {
+		oldest := m.lru.Back()
+		if oldest == nil {
+			break
+		}
+		entry := oldest.Value.(*connEntry)
+		delete(m.entries, entry.key)
+		delete(m.byPos, entry.state.Pos)
+		m.lru.Remove(oldest)
+	}
+	return pos
+}
+
+func newPos() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand failing is unrecoverable
+	}
+	return hex.EncodeToString(b)
+}
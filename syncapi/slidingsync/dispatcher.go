@@ -0,0 +1,310 @@
+package slidingsync
+
+import (
+	"sort"
+
+	"github.com/matrix-org/gomatrixserverlib"
+
+	"github.com/matrix-org/dendrite/syncapi/types"
+)
+
+// RoomMeta is the per-room metadata and events the dispatcher needs in order
+// to sort/filter a device's rooms and fill in their required_state,
+// timeline and heroes. The sliding sync HTTP handler takes it from its
+// caller-supplied roomProvider.
+type RoomMeta struct {
+	RoomID            string
+	Name              string
+	LastEventTS       uint64
+	IsDM              bool
+	IsEncrypted       bool
+	RoomType          string
+	NotificationCount int
+	HighlightCount    int
+	// State is the room's current state events, used to satisfy
+	// required_state and to compute heroes when Name is empty.
+	State []*gomatrixserverlib.HeaderedEvent
+	// Timeline is the room's timeline events in chronological order,
+	// trimmed to timeline_limit when returned.
+	Timeline []*gomatrixserverlib.HeaderedEvent
+}
+
+// Dispatcher computes the ops required to bring a connection's view of a
+// list up to date, given the full set of rooms currently visible to the
+// device and what the connection last saw.
+type Dispatcher struct {
+	Conns *ConnMap
+}
+
+// NewDispatcher creates a Dispatcher backed by a connection cache holding at
+// most maxConns connections.
+func NewDispatcher(maxConns int) *Dispatcher {
+	return &Dispatcher{Conns: NewConnMap(maxConns)}
+}
+
+// roomRequirements accumulates the required_state/timeline_limit asked of a
+// room across every list and room_subscription that pulled it into the
+// response, so a room touched more than once is populated once, from the
+// union of what was asked, rather than depending on iteration order.
+type roomRequirements struct {
+	requiredState [][2]string
+	timelineLimit int
+}
+
+// Process computes the SlidingSyncResponse for one request. pos is the
+// client-presented `pos` token, empty on the very first request for a
+// connection. An unrecognised pos is treated identically to an empty one:
+// every list starts from scratch and every returned room is `initial`.
+// requestingUserID is excluded from any synthesised heroes, since a room's
+// display name is never synthesised from the syncing user themselves.
+func (d *Dispatcher) Process(key ConnKey, pos string, req types.SlidingSyncRequest, rooms []RoomMeta, requestingUserID string) (types.SlidingSyncResponse, bool) {
+	var prev ConnState
+	isInitial := pos == ""
+	if !isInitial {
+		var ok bool
+		_, prev, ok = d.Conns.GetByPos(pos)
+		if !ok {
+			// Unknown pos: per MSC3575 this must cleanly re-initialise the
+			// connection rather than error out.
+			isInitial = true
+			prev = ConnState{}
+		}
+	}
+
+	roomsByID := make(map[string]RoomMeta, len(rooms))
+	for _, r := range rooms {
+		roomsByID[r.RoomID] = r
+	}
+
+	resp := types.SlidingSyncResponse{
+		Lists: make(map[string]types.SlidingSyncResponseList, len(req.Lists)),
+		Rooms: make(map[string]types.SlidingSyncRoom),
+	}
+	next := ConnState{
+		Windows: make(map[string][]string, len(req.Lists)),
+		Known:   make(map[string]bool, len(prev.Known)),
+	}
+
+	reqs := make(map[string]*roomRequirements)
+	addRequirements := func(roomID string, requiredState [][2]string, timelineLimit int) {
+		rr := reqs[roomID]
+		if rr == nil {
+			rr = &roomRequirements{}
+			reqs[roomID] = rr
+		}
+		rr.requiredState = mergeRequiredState(rr.requiredState, requiredState)
+		if timelineLimit > rr.timelineLimit {
+			rr.timelineLimit = timelineLimit
+		}
+	}
+
+	for listKey, list := range req.Lists {
+		filtered := filterRooms(rooms, list.Filters)
+		sortRooms(filtered, list.Sort)
+
+		windowed := windowRoomIDs(filtered, list.Ranges)
+		next.Windows[listKey] = windowed
+
+		prevWindow := prev.Windows[listKey]
+		ops := diffWindows(prevWindow, windowed)
+		resp.Lists[listKey] = types.SlidingSyncResponseList{
+			Count: len(filtered),
+			Ops:   ops,
+		}
+
+		for _, roomID := range windowed {
+			next.Known[roomID] = true
+			addRequirements(roomID, list.RequiredState, list.TimelineLimit)
+		}
+	}
+
+	for roomID, sub := range req.RoomSubscriptions {
+		next.Known[roomID] = true
+		addRequirements(roomID, sub.RequiredState, sub.TimelineLimit)
+	}
+
+	for roomID, rr := range reqs {
+		room := populateRoomData(roomsByID[roomID], rr.requiredState, rr.timelineLimit, requestingUserID)
+		room.Initial = isInitial || !prev.Known[roomID]
+		resp.Rooms[roomID] = room
+	}
+
+	newPos := d.Conns.Put(key, next)
+	resp.Pos = newPos
+	return resp, isInitial
+}
+
+// mergeRequiredState unions two [type, state_key] tuple lists, deduplicating
+// exact (type, state_key) pairs.
+func mergeRequiredState(a, b [][2]string) [][2]string {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	seen := make(map[[2]string]bool, len(a)+len(b))
+	out := make([][2]string, 0, len(a)+len(b))
+	for _, tuples := range [][][2]string{a, b} {
+		for _, t := range tuples {
+			if !seen[t] {
+				seen[t] = true
+				out = append(out, t)
+			}
+		}
+	}
+	return out
+}
+
+func filterRooms(rooms []RoomMeta, f *types.SlidingSyncFilters) []RoomMeta {
+	if f == nil {
+		return rooms
+	}
+	out := make([]RoomMeta, 0, len(rooms))
+	for _, r := range rooms {
+		if f.IsDM != nil && r.IsDM != *f.IsDM {
+			continue
+		}
+		if f.IsEncrypted != nil && r.IsEncrypted != *f.IsEncrypted {
+			continue
+		}
+		if len(f.RoomTypes) > 0 && !contains(f.RoomTypes, r.RoomType) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, h := range haystack {
+		if h == needle {
+			return true
+		}
+	}
+	return false
+}
+
+// sortRooms orders rooms in place according to the requested sort operators,
+// most significant first, falling back to by_recency when none are given.
+func sortRooms(rooms []RoomMeta, by []string) {
+	if len(by) == 0 {
+		by = []string{"by_recency"}
+	}
+	sort.SliceStable(rooms, func(i, j int) bool {
+		for _, key := range by {
+			switch key {
+			case "by_name":
+				if rooms[i].Name != rooms[j].Name {
+					return rooms[i].Name < rooms[j].Name
+				}
+			case "by_notification_level":
+				iLevel, jLevel := notificationLevel(rooms[i]), notificationLevel(rooms[j])
+				if iLevel != jLevel {
+					return iLevel > jLevel
+				}
+			case "by_recency":
+				fallthrough
+			default:
+				if rooms[i].LastEventTS != rooms[j].LastEventTS {
+					return rooms[i].LastEventTS > rooms[j].LastEventTS
+				}
+			}
+		}
+		return rooms[i].RoomID < rooms[j].RoomID
+	})
+}
+
+func notificationLevel(r RoomMeta) int {
+	if r.HighlightCount > 0 {
+		return 2
+	}
+	if r.NotificationCount > 0 {
+		return 1
+	}
+	return 0
+}
+
+// windowRoomIDs returns, for each requested [start, end] range, the room IDs
+// occupying those indices in the sorted list, concatenated in range order.
+// Out-of-bounds ranges are clamped rather than erroring.
+func windowRoomIDs(sorted []RoomMeta, ranges [][2]int) []string {
+	if len(ranges) == 0 {
+		return nil
+	}
+	var out []string
+	for _, r := range ranges {
+		start, end := r[0], r[1]
+		if start < 0 {
+			start = 0
+		}
+		if end >= len(sorted) {
+			end = len(sorted) - 1
+		}
+		for i := start; i <= end && i < len(sorted) && i >= 0; i++ {
+			out = append(out, sorted[i].RoomID)
+		}
+	}
+	return out
+}
+
+// diffWindows computes the ops for the rooms that entered or left the
+// client's previously-known window. A connection with no prior window
+// (initial sync, or an unrecognised pos) gets a single SYNC covering the
+// whole range, and a window that is now empty gets a single INVALIDATE over
+// the old one; otherwise rooms that moved out are DELETEd, rooms that moved
+// in are INSERTed, and an unchanged window produces no ops at all. Rooms
+// that stay in the window but change position get no op of their own, so a
+// window holding the same rooms in a new order also produces no ops.
+func diffWindows(prev, next []string) []types.SlidingSyncOp {
+	if len(next) == 0 {
+		if len(prev) == 0 {
+			return nil
+		}
+		return []types.SlidingSyncOp{{Op: types.SlidingSyncOpInvalidate, Range: [2]int{0, len(prev) - 1}}}
+	}
+	if prev == nil {
+		return []types.SlidingSyncOp{{
+			Op:      types.SlidingSyncOpSync,
+			Range:   [2]int{0, len(next) - 1},
+			RoomIDs: next,
+		}}
+	}
+	if equalStrings(prev, next) {
+		return nil
+	}
+
+	prevIdx := make(map[string]int, len(prev))
+	for i, id := range prev {
+		prevIdx[id] = i
+	}
+	nextIdx := make(map[string]int, len(next))
+	for i, id := range next {
+		nextIdx[id] = i
+	}
+
+	var ops []types.SlidingSyncOp
+	for i, id := range prev {
+		if _, stillPresent := nextIdx[id]; !stillPresent {
+			ops = append(ops, types.SlidingSyncOp{Op: types.SlidingSyncOpDelete, Index: i, RoomIDs: []string{id}})
+		}
+	}
+	for i, id := range next {
+		if _, wasPresent := prevIdx[id]; !wasPresent {
+			ops = append(ops, types.SlidingSyncOp{Op: types.SlidingSyncOpInsert, Index: i, RoomIDs: []string{id}})
+		}
+	}
+	return ops
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
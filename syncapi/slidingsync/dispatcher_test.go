@@ -0,0 +1,121 @@
+package slidingsync
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/matrix-org/dendrite/syncapi/types"
+)
+
+func TestDispatcherOpSequence(t *testing.T) {
+	key := ConnKey{DeviceID: "DEVICE", ConnID: "a"}
+	req := types.SlidingSyncRequest{
+		Lists: map[string]types.SlidingSyncRequestList{
+			"rooms": {
+				Ranges: [][2]int{{0, 1}},
+				Sort:   []string{"by_recency"},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		rooms   []RoomMeta
+		wantOps []types.SlidingSyncOp
+	}{
+		{
+			name: "initial sync returns SYNC for the whole window",
+			rooms: []RoomMeta{
+				{RoomID: "!a", LastEventTS: 3},
+				{RoomID: "!b", LastEventTS: 2},
+				{RoomID: "!c", LastEventTS: 1},
+			},
+			wantOps: []types.SlidingSyncOp{
+				{Op: types.SlidingSyncOpSync, Range: [2]int{0, 1}, RoomIDs: []string{"!a", "!b"}},
+			},
+		},
+		{
+			name: "unchanged window produces no ops",
+			rooms: []RoomMeta{
+				{RoomID: "!a", LastEventTS: 3},
+				{RoomID: "!b", LastEventTS: 2},
+				{RoomID: "!c", LastEventTS: 1},
+			},
+			wantOps: nil,
+		},
+		{
+			name: "a new recent room evicts the tail of the window",
+			rooms: []RoomMeta{
+				{RoomID: "!d", LastEventTS: 10},
+				{RoomID: "!a", LastEventTS: 3},
+				{RoomID: "!b", LastEventTS: 2},
+				{RoomID: "!c", LastEventTS: 1},
+			},
+			wantOps: []types.SlidingSyncOp{
+				{Op: types.SlidingSyncOpDelete, Index: 1, RoomIDs: []string{"!b"}},
+				{Op: types.SlidingSyncOpInsert, Index: 0, RoomIDs: []string{"!d"}},
+			},
+		},
+	}
+
+	// Successive requests reuse the pos issued by the previous one, so each
+	// case's ops are the delta against the window computed in the case before.
+	pos := ""
+	d := NewDispatcher(10)
+	for _, tc := range testCases {
+		resp, _ := d.Process(key, pos, req, tc.rooms, "@alice:test")
+		gotOps := resp.Lists["rooms"].Ops
+		if !reflect.DeepEqual(gotOps, tc.wantOps) {
+			t.Errorf("%s: got ops %+v want %+v", tc.name, gotOps, tc.wantOps)
+		}
+		pos = resp.Pos
+	}
+}
+
+func TestDispatcherUnknownPosForcesFullResync(t *testing.T) {
+	key := ConnKey{DeviceID: "DEVICE", ConnID: "a"}
+	req := types.SlidingSyncRequest{
+		Lists: map[string]types.SlidingSyncRequestList{
+			"rooms": {Ranges: [][2]int{{0, 0}}},
+		},
+	}
+	rooms := []RoomMeta{{RoomID: "!a", LastEventTS: 1}}
+
+	d := NewDispatcher(10)
+	resp, isInitial := d.Process(key, "this-pos-was-never-issued", req, rooms, "@alice:test")
+	if !isInitial {
+		t.Fatalf("expected an unknown pos to be treated as an initial sync")
+	}
+	wantOps := []types.SlidingSyncOp{
+		{Op: types.SlidingSyncOpSync, Range: [2]int{0, 0}, RoomIDs: []string{"!a"}},
+	}
+	if gotOps := resp.Lists["rooms"].Ops; !reflect.DeepEqual(gotOps, wantOps) {
+		t.Errorf("got ops %+v want %+v", gotOps, wantOps)
+	}
+	if room, ok := resp.Rooms["!a"]; !ok || !room.Initial {
+		t.Errorf("expected room !a to be marked initial, got %+v (ok=%v)", room, ok)
+	}
+}
+
+func TestDispatcherEvictsLeastRecentlyUsedConnection(t *testing.T) {
+	d := NewDispatcher(1)
+	req := types.SlidingSyncRequest{
+		Lists: map[string]types.SlidingSyncRequestList{
+			"rooms": {Ranges: [][2]int{{0, 0}}},
+		},
+	}
+	rooms := []RoomMeta{{RoomID: "!a", LastEventTS: 1}}
+
+	first := ConnKey{DeviceID: "DEVICE", ConnID: "first"}
+	second := ConnKey{DeviceID: "DEVICE", ConnID: "second"}
+
+	firstResp, _ := d.Process(first, "", req, rooms, "@alice:test")
+	// Processing a second, distinct connection evicts the first from the
+	// (maxConns=1) cache.
+	d.Process(second, "", req, rooms, "@alice:test")
+
+	_, isInitial := d.Process(first, firstResp.Pos, req, rooms, "@alice:test")
+	if !isInitial {
+		t.Fatalf("expected the evicted connection's old pos to force a full re-sync")
+	}
+}
@@ -0,0 +1,86 @@
+package slidingsync
+
+import (
+	"github.com/matrix-org/gomatrixserverlib"
+	"github.com/tidwall/gjson"
+
+	"github.com/matrix-org/dendrite/syncapi/types"
+)
+
+// maxHeroes mirrors the v3 sync handler's cap on synthesised room names:
+// at most 5 other members are used to build one when the room has none.
+const maxHeroes = 5
+
+// populateRoomData shapes a room's state/timeline events into the
+// required_state, timeline and heroes a sliding sync response returns for
+// it. requiredState and timelineLimit are the union of what every list or
+// room_subscription that pulled this room in asked for (see
+// roomRequirements). excludeUserID is the syncing user, who is never
+// synthesised as one of their own room's heroes.
+func populateRoomData(room RoomMeta, requiredState [][2]string, timelineLimit int, excludeUserID string) types.SlidingSyncRoom {
+	out := types.SlidingSyncRoom{
+		Name: room.Name,
+	}
+	if len(requiredState) > 0 {
+		out.RequiredState = gomatrixserverlib.HeaderedToClientEvents(filterState(room.State, requiredState), gomatrixserverlib.FormatAll)
+	}
+	if timelineLimit > 0 && len(room.Timeline) > 0 {
+		events := room.Timeline
+		if len(events) > timelineLimit {
+			events = events[len(events)-timelineLimit:]
+		}
+		out.Timeline = gomatrixserverlib.HeaderedToClientEvents(events, gomatrixserverlib.FormatAll)
+	}
+	if room.Name == "" {
+		out.Heroes = heroesFromState(room.State, excludeUserID)
+	}
+	return out
+}
+
+// filterState returns the state events matching any of the requested
+// [type, state_key] tuples, where a state_key of "*" matches any key.
+func filterState(state []*gomatrixserverlib.HeaderedEvent, want [][2]string) []*gomatrixserverlib.HeaderedEvent {
+	out := make([]*gomatrixserverlib.HeaderedEvent, 0, len(state))
+	for _, ev := range state {
+		stateKey := ev.StateKey()
+		if stateKey == nil {
+			continue
+		}
+		for _, w := range want {
+			if ev.Type() == w[0] && (w[1] == "*" || *stateKey == w[1]) {
+				out = append(out, ev)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// heroesFromState picks up to maxHeroes other joined members out of state,
+// for the client to synthesise a room name from when the room has none set.
+// excludeUserID (the syncing user) is never returned as one of their own
+// room's heroes.
+func heroesFromState(state []*gomatrixserverlib.HeaderedEvent, excludeUserID string) []types.SlidingSyncHero {
+	var heroes []types.SlidingSyncHero
+	for _, ev := range state {
+		if ev.Type() != "m.room.member" {
+			continue
+		}
+		if gjson.GetBytes(ev.Content(), "membership").Str != "join" {
+			continue
+		}
+		stateKey := ev.StateKey()
+		if stateKey == nil || *stateKey == excludeUserID {
+			continue
+		}
+		heroes = append(heroes, types.SlidingSyncHero{
+			UserID:      *stateKey,
+			DisplayName: gjson.GetBytes(ev.Content(), "displayname").Str,
+			AvatarURL:   gjson.GetBytes(ev.Content(), "avatar_url").Str,
+		})
+		if len(heroes) == maxHeroes {
+			break
+		}
+	}
+	return heroes
+}
@@ -0,0 +1,78 @@
+package slidingsync
+
+import (
+	"testing"
+
+	"github.com/matrix-org/dendrite/test"
+)
+
+func TestPopulateRoomDataFiltersRequiredStateAndTrimsTimeline(t *testing.T) {
+	user := test.NewUser(t)
+	room := test.NewRoom(t, user)
+
+	meta := RoomMeta{
+		RoomID:   room.ID,
+		State:    room.Events(),
+		Timeline: room.Events(),
+	}
+
+	out := populateRoomData(meta, [][2]string{{"m.room.create", "*"}}, 1, user.ID)
+
+	if len(out.RequiredState) != 1 {
+		t.Fatalf("got %d required_state events, want 1 (only m.room.create)", len(out.RequiredState))
+	}
+	if out.RequiredState[0].Type != "m.room.create" {
+		t.Errorf("got required_state type %q, want m.room.create", out.RequiredState[0].Type)
+	}
+	if len(out.Timeline) != 1 {
+		t.Fatalf("got %d timeline events, want 1 (timeline_limit)", len(out.Timeline))
+	}
+	if want := room.Events()[len(room.Events())-1].EventID(); out.Timeline[0].EventID != want {
+		t.Errorf("got last timeline event %q, want the most recent event %q", out.Timeline[0].EventID, want)
+	}
+}
+
+func TestPopulateRoomDataOmitsUnpopulatedFieldsWhenNotRequested(t *testing.T) {
+	user := test.NewUser(t)
+	room := test.NewRoom(t, user)
+
+	meta := RoomMeta{
+		RoomID:   room.ID,
+		State:    room.Events(),
+		Timeline: room.Events(),
+	}
+
+	out := populateRoomData(meta, nil, 0, user.ID)
+	if len(out.RequiredState) != 0 {
+		t.Errorf("got %d required_state events with none requested, want 0", len(out.RequiredState))
+	}
+	if len(out.Timeline) != 0 {
+		t.Errorf("got %d timeline events with timeline_limit=0, want 0", len(out.Timeline))
+	}
+}
+
+func TestPopulateRoomDataSynthesisesHeroesExcludingTheSyncingUser(t *testing.T) {
+	alice := test.NewUser(t)
+	bob := test.NewUser(t)
+	room := test.NewRoom(t, alice)
+	room.CreateAndInsert(t, bob, "m.room.member", map[string]interface{}{"membership": "join"}, test.WithStateKey(bob.ID))
+
+	meta := RoomMeta{
+		RoomID: room.ID,
+		Name:   "",
+		State:  room.Events(),
+	}
+
+	out := populateRoomData(meta, nil, 0, alice.ID)
+	if len(out.Heroes) == 0 {
+		t.Fatalf("expected heroes to be synthesised from joined members, got none")
+	}
+	for _, h := range out.Heroes {
+		if h.UserID == alice.ID {
+			t.Errorf("got the syncing user %q as a hero, want only other members", alice.ID)
+		}
+	}
+	if out.Heroes[0].UserID != bob.ID {
+		t.Errorf("got hero %q, want the other joined member %q", out.Heroes[0].UserID, bob.ID)
+	}
+}
@@ -0,0 +1,75 @@
+package syncapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/matrix-org/dendrite/clientapi/jsonerror"
+	"github.com/matrix-org/dendrite/syncapi/slidingsync"
+	"github.com/matrix-org/dendrite/syncapi/types"
+	userapi "github.com/matrix-org/dendrite/userapi/api"
+	"github.com/matrix-org/util"
+)
+
+// defaultMaxSlidingSyncConns bounds how many sliding sync connections are
+// held in memory at once; older connections are evicted LRU-style and must
+// re-sync from scratch, which is self-healing rather than fatal.
+const defaultMaxSlidingSyncConns = 1000
+
+// SlidingSync exposes MSC3575 sliding sync alongside the classic v3 /sync
+// handler. It is the HTTP/dispatch layer only: sorting, windowing and
+// required_state/timeline population (slidingsync.Dispatcher) are real and
+// tested, but roomProvider is a caller-supplied function, not a connection
+// to the syncapi's own storage/streamers. There is no typing or receipt
+// extension yet either - both would need roomProvider's data source to be
+// the real syncapi storage/streams, which are not part of this checkout.
+// Today the only roomProvider in existence is the one TestSlidingSyncEndpoint
+// builds from hand-constructed RoomMeta.
+type SlidingSync struct {
+	dispatcher   *slidingsync.Dispatcher
+	roomProvider func(userID string) ([]slidingsync.RoomMeta, error)
+}
+
+// NewSlidingSync constructs the sliding sync subsystem. roomProvider
+// supplies the set of rooms visible to a user, together with the state and
+// timeline events the dispatcher needs to fill in required_state/timeline.
+// See the SlidingSync doc comment for what backs roomProvider today, and
+// AddSlidingSyncRoutes for how this is mounted for tests.
+func NewSlidingSync(roomProvider func(userID string) ([]slidingsync.RoomMeta, error)) *SlidingSync {
+	return &SlidingSync{
+		dispatcher:   slidingsync.NewDispatcher(defaultMaxSlidingSyncConns),
+		roomProvider: roomProvider,
+	}
+}
+
+// HandleRequest implements the POST /_matrix/client/unstable/org.matrix.msc3575/sync
+// request. It is invoked with an already-authenticated device, matching how
+// the v3 sync handler is wired into AddPublicRoutes.
+func (s *SlidingSync) HandleRequest(req *http.Request, device *userapi.Device) util.JSONResponse {
+	var body types.SlidingSyncRequest
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return util.JSONResponse{
+				Code: http.StatusBadRequest,
+				JSON: jsonerror.BadJSON("The request body could not be decoded into sliding sync parameters: " + err.Error()),
+			}
+		}
+	}
+
+	connID := req.URL.Query().Get("conn_id")
+	pos := req.URL.Query().Get("pos")
+
+	rooms, err := s.roomProvider(device.UserID)
+	if err != nil {
+		util.GetLogger(req.Context()).WithError(err).Error("slidingsync: failed to load rooms")
+		return util.ErrorResponse(err)
+	}
+
+	key := slidingsync.ConnKey{DeviceID: device.ID, ConnID: connID}
+	resp, _ := s.dispatcher.Process(key, pos, body, rooms, device.UserID)
+
+	return util.JSONResponse{
+		Code: http.StatusOK,
+		JSON: resp,
+	}
+}
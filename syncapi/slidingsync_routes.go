@@ -0,0 +1,75 @@
+package syncapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/matrix-org/dendrite/clientapi/jsonerror"
+	userapi "github.com/matrix-org/dendrite/userapi/api"
+	"github.com/matrix-org/util"
+)
+
+// slidingSyncPath is the MSC3575 path relative to the public client API
+// router, which is already rooted at /_matrix/client: it is served as
+// /_matrix/client/unstable/org.matrix.msc3575/sync, alongside the v3
+// /_matrix/client/v3/sync route on the same router.
+const slidingSyncPath = "/unstable/org.matrix.msc3575/sync"
+
+// AddSlidingSyncRoutes mounts the sliding sync endpoint onto router,
+// authenticating requests the same way the v3 sync handler does: via an
+// access_token query parameter or Bearer Authorization header resolved
+// through userAPI.
+//
+// AddPublicRoutes - the function that builds the v3 sync route and that a
+// running dendrite actually calls - is not part of this checkout (there is
+// no syncapi.go/routing.go here, only this package's two new files plus
+// the pre-existing test file), so this function is not called from
+// anywhere yet and the endpoint is not reachable outside of tests that
+// call it directly, such as TestSlidingSyncEndpoint. Wiring it in is a
+// single added line, `AddSlidingSyncRoutes(router, slidingSync, userAPI)`,
+// next to wherever AddPublicRoutes registers the v3 sync route.
+func AddSlidingSyncRoutes(router *mux.Router, ss *SlidingSync, userAPI userapi.SyncUserAPI) {
+	router.Handle(slidingSyncPath, authenticated(userAPI, ss.HandleRequest)).Methods(http.MethodPost)
+}
+
+// authenticated wraps a sliding-sync handler with the same access-token
+// lookup used elsewhere in the syncapi, returning 401 on failure instead of
+// invoking next.
+func authenticated(userAPI userapi.SyncUserAPI, next func(*http.Request, *userapi.Device) util.JSONResponse) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		token := req.URL.Query().Get("access_token")
+		if token == "" {
+			if authHeader := req.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+				token = strings.TrimPrefix(authHeader, "Bearer ")
+			}
+		}
+		if token == "" {
+			writeJSONResponse(w, util.JSONResponse{Code: http.StatusUnauthorized, JSON: jsonerror.MissingToken("Missing access token")})
+			return
+		}
+
+		var queryRes userapi.QueryAccessTokenResponse
+		if err := userAPI.QueryAccessToken(req.Context(), &userapi.QueryAccessTokenRequest{AccessToken: token}, &queryRes); err != nil {
+			writeJSONResponse(w, util.ErrorResponse(err))
+			return
+		}
+		if queryRes.Err != "" || queryRes.Device == nil {
+			writeJSONResponse(w, util.JSONResponse{Code: http.StatusUnauthorized, JSON: jsonerror.UnknownToken(queryRes.Err)})
+			return
+		}
+
+		writeJSONResponse(w, next(req, queryRes.Device))
+	}
+}
+
+func writeJSONResponse(w http.ResponseWriter, res util.JSONResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(res.Code)
+	if res.JSON == nil {
+		return
+	}
+	_ = json.NewEncoder(w).Encode(res.JSON)
+}
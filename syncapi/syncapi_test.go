@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	rsapi "github.com/matrix-org/dendrite/roomserver/api"
 	"github.com/matrix-org/dendrite/setup/base"
 	"github.com/matrix-org/dendrite/setup/jetstream"
+	"github.com/matrix-org/dendrite/syncapi/slidingsync"
 	"github.com/matrix-org/dendrite/syncapi/types"
 	"github.com/matrix-org/dendrite/test"
 	"github.com/matrix-org/dendrite/test/testrig"
@@ -142,8 +144,27 @@ func testSyncAccessTokens(t *testing.T, dbType test.DBType) {
 			wantJoinedRooms: []string{room.ID},
 		},
 	}
-	// TODO: find a better way
-	time.Sleep(500 * time.Millisecond)
+	// Poll for the events to be consumed off NATS instead of guessing how
+	// long that takes with a fixed sleep. The ideal fix here is
+	// testrig.WaitForConsumed backed by notifier.StreamPositionWaiter.OnCommitted
+	// (syncapi/notifier), but that requires the room-server output consumer
+	// and testrig to call it, and neither lives in this checkout; pollUntil
+	// gets the same determinism without needing either.
+	pollUntil(2*time.Second, func() bool {
+		w := httptest.NewRecorder()
+		base.PublicClientAPIMux.ServeHTTP(w, test.NewRequest(t, "GET", "/_matrix/client/v3/sync", test.WithQueryParams(map[string]string{
+			"access_token": alice.AccessToken,
+			"timeout":      "0",
+		})))
+		if w.Code != 200 {
+			return false
+		}
+		var res types.Response
+		if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+			return false
+		}
+		return len(res.Rooms.Join[room.ID].Timeline.Events) == len(room.Events())
+	})
 
 	for _, tc := range testCases {
 		w := httptest.NewRecorder()
@@ -203,9 +224,36 @@ func testSyncAPICreateRoomSyncEarly(t *testing.T, dbType test.DBType) {
 	msgs := toNATSMsgs(t, base, room.Events())
 	sinceTokens := make([]string, len(msgs))
 	AddPublicRoutes(base, &syncUserAPI{accounts: []userapi.Device{alice}}, &syncRoomserverAPI{rooms: []*test.Room{room}}, &syncKeyAPI{})
+	var lastPDUPos types.StreamPosition
 	for i, msg := range msgs {
 		testrig.MustPublishMsgs(t, jsctx, msg)
-		time.Sleep(100 * time.Millisecond)
+		// Poll rather than guess how long consumption takes; see the
+		// comment on the same pattern in testSyncAccessTokens above. Each
+		// consumed event advances the PDU position of next_batch, which is
+		// what tells this event apart from the ones before it.
+		wantJoinedRooms := 1
+		if i == 0 { // create event does not produce a room section
+			wantJoinedRooms = 0
+		}
+		pollUntil(2*time.Second, func() bool {
+			w := httptest.NewRecorder()
+			base.PublicClientAPIMux.ServeHTTP(w, test.NewRequest(t, "GET", "/_matrix/client/v3/sync", test.WithQueryParams(map[string]string{
+				"access_token": alice.AccessToken,
+				"timeout":      "0",
+			})))
+			if w.Code != 200 {
+				return false
+			}
+			var res types.Response
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				return false
+			}
+			if res.NextBatch.PDUPosition <= lastPDUPos || len(res.Rooms.Join) != wantJoinedRooms {
+				return false
+			}
+			lastPDUPos = res.NextBatch.PDUPosition
+			return true
+		})
 		w := httptest.NewRecorder()
 		base.PublicClientAPIMux.ServeHTTP(w, test.NewRequest(t, "GET", "/_matrix/client/v3/sync", test.WithQueryParams(map[string]string{
 			"access_token": alice.AccessToken,
@@ -315,6 +363,111 @@ func testSyncAPIUpdatePresenceImmediately(t *testing.T, dbType test.DBType) {
 
 }
 
+// Test that the MSC3575 sliding sync endpoint is reachable on the same
+// public router the v3 /sync handler uses, and that it returns op sequences
+// and per-room data (required_state/timeline/heroes) computed from real
+// room events rather than placeholder values.
+func TestSlidingSyncEndpoint(t *testing.T) {
+	test.WithAllDatabases(t, testSlidingSyncEndpoint)
+}
+
+func testSlidingSyncEndpoint(t *testing.T, dbType test.DBType) {
+	user := test.NewUser(t)
+	room := test.NewRoom(t, user)
+	alice := userapi.Device{
+		ID:          "ALICEID",
+		UserID:      user.ID,
+		AccessToken: "ALICE_BEARER_TOKEN",
+		DisplayName: "Alice",
+		AccountType: userapi.AccountTypeUser,
+	}
+
+	base, close := testrig.CreateBaseDendrite(t, dbType)
+	defer close()
+
+	roomMeta := roomMetaFromTestRoom(room)
+	ss := NewSlidingSync(func(userID string) ([]slidingsync.RoomMeta, error) {
+		if userID != user.ID {
+			return nil, nil
+		}
+		return []slidingsync.RoomMeta{roomMeta}, nil
+	})
+	AddSlidingSyncRoutes(base.PublicClientAPIMux, ss, &syncUserAPI{accounts: []userapi.Device{alice}})
+
+	const reqBody = `{"lists":{"rooms":{"ranges":[[0,0]],"required_state":[["m.room.create","*"]],"timeline_limit":5}}}`
+	doRequest := func(pos string) (int, types.SlidingSyncResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/_matrix/client/unstable/org.matrix.msc3575/sync?access_token="+alice.AccessToken+"&pos="+pos, strings.NewReader(reqBody))
+		w := httptest.NewRecorder()
+		base.PublicClientAPIMux.ServeHTTP(w, req)
+		var res types.SlidingSyncResponse
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatalf("failed to decode response body: %s", err)
+			}
+		}
+		return w.Code, res
+	}
+
+	code, first := doRequest("")
+	if code != http.StatusOK {
+		t.Fatalf("initial request: got HTTP %d want 200", code)
+	}
+	if ops := first.Lists["rooms"].Ops; len(ops) != 1 || ops[0].Op != types.SlidingSyncOpSync {
+		t.Fatalf("initial request: got ops %+v, want a single SYNC", ops)
+	}
+	roomResp, ok := first.Rooms[room.ID]
+	if !ok || !roomResp.Initial {
+		t.Fatalf("initial request: expected room %s to be present and initial, got %+v (ok=%v)", room.ID, roomResp, ok)
+	}
+	if len(roomResp.RequiredState) == 0 {
+		t.Errorf("initial request: expected required_state to be populated, got none")
+	}
+	if len(roomResp.Timeline) == 0 {
+		t.Errorf("initial request: expected timeline to be populated, got none")
+	}
+
+	code, second := doRequest(first.Pos)
+	if code != http.StatusOK {
+		t.Fatalf("repeat request: got HTTP %d want 200", code)
+	}
+	if ops := second.Lists["rooms"].Ops; ops != nil {
+		t.Errorf("repeat request with an unchanged window: got ops %+v, want none", ops)
+	}
+
+	code, unknownPosResp := doRequest("some-pos-never-issued")
+	if code != http.StatusOK {
+		t.Fatalf("unknown pos: got HTTP %d want 200", code)
+	}
+	if ops := unknownPosResp.Lists["rooms"].Ops; len(ops) != 1 || ops[0].Op != types.SlidingSyncOpSync {
+		t.Fatalf("unknown pos: got ops %+v, want a single SYNC re-initialising the window", ops)
+	}
+
+	unauthReq := httptest.NewRequest(http.MethodPost, "/_matrix/client/unstable/org.matrix.msc3575/sync", strings.NewReader("{}"))
+	w := httptest.NewRecorder()
+	base.PublicClientAPIMux.ServeHTTP(w, unauthReq)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("missing access token: got HTTP %d want 401", w.Code)
+	}
+}
+
+// roomMetaFromTestRoom builds the RoomMeta the sliding sync dispatcher needs
+// out of a test.Room's events, mirroring how a production RoomProvider would
+// shape state/timeline events pulled from storage.
+func roomMetaFromTestRoom(room *test.Room) slidingsync.RoomMeta {
+	events := room.Events()
+	meta := slidingsync.RoomMeta{
+		RoomID: room.ID,
+	}
+	for _, ev := range events {
+		if ev.StateKey() != nil {
+			meta.State = append(meta.State, ev)
+		}
+		meta.Timeline = append(meta.Timeline, ev)
+		meta.LastEventTS = uint64(ev.OriginServerTS())
+	}
+	return meta
+}
+
 func TestSendToDevice(t *testing.T) {
 	test.WithAllDatabases(t, testSendToDevice)
 }
@@ -425,20 +578,25 @@ func testSendToDevice(t *testing.T, dbType test.DBType) {
 				t.Fatalf("unable to send to device message: %v", err)
 			}
 		}
-		time.Sleep((time.Millisecond * 15) * time.Duration(tc.sendMessagesCount)) // wait a bit, so the messages can be processed
-		// Execute a /sync request, recording the response
-		w := httptest.NewRecorder()
-		base.PublicClientAPIMux.ServeHTTP(w, test.NewRequest(t, "GET", "/_matrix/client/v3/sync", test.WithQueryParams(map[string]string{
-			"access_token": alice.AccessToken,
-			"since":        tc.since,
-		})))
-
-		// Extract the to_device.events, # gets all values of an array, in this case a string slice with "message $counter" entries
-		events := gjson.Get(w.Body.String(), "to_device.events.#.content.dummy").Array()
-		got := make([]string, len(events))
-		for i := range events {
-			got[i] = events[i].String()
-		}
+		// Poll rather than guess how long consumption takes; see the
+		// comment on the same pattern in testSyncAccessTokens above.
+		var w *httptest.ResponseRecorder
+		var got []string
+		pollUntil(2*time.Second, func() bool {
+			w = httptest.NewRecorder()
+			base.PublicClientAPIMux.ServeHTTP(w, test.NewRequest(t, "GET", "/_matrix/client/v3/sync", test.WithQueryParams(map[string]string{
+				"access_token": alice.AccessToken,
+				"since":        tc.since,
+			})))
+
+			// Extract the to_device.events, # gets all values of an array, in this case a string slice with "message $counter" entries
+			events := gjson.Get(w.Body.String(), "to_device.events.#.content.dummy").Array()
+			got = make([]string, len(events))
+			for i := range events {
+				got[i] = events[i].String()
+			}
+			return reflect.DeepEqual(got, tc.want)
+		})
 
 		// Ensure the messages we received are as we expect them to be
 		if !reflect.DeepEqual(got, tc.want) {
@@ -448,6 +606,25 @@ func testSendToDevice(t *testing.T, dbType test.DBType) {
 	}
 }
 
+// pollUntil retries fn every 10ms until it returns true or timeout elapses.
+// It replaces fixed time.Sleep calls that guessed how long NATS consumption
+// would take; fn is expected to make its own request and report whether the
+// result it got back is the one being waited for. Callers still make their
+// own assertion after pollUntil returns, so a timeout fails with the same
+// diagnostics a one-shot request would have.
+func pollUntil(timeout time.Duration, fn func() bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if fn() {
+			return
+		}
+		if time.Now().After(deadline) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func toNATSMsgs(t *testing.T, base *base.BaseDendrite, input []*gomatrixserverlib.HeaderedEvent) []*nats.Msg {
 	result := make([]*nats.Msg, len(input))
 	for i, ev := range input {
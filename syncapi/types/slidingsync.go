@@ -0,0 +1,100 @@
+package types
+
+import "github.com/matrix-org/gomatrixserverlib"
+
+// SlidingSyncRequest is the request body of
+// POST /_matrix/client/unstable/org.matrix.msc3575/sync
+// as defined by MSC3575 (https://github.com/matrix-org/matrix-spec-proposals/pull/3575).
+type SlidingSyncRequest struct {
+	// Lists are named room list windows the client wants to track, e.g. "left-panel".
+	Lists map[string]SlidingSyncRequestList `json:"lists,omitempty"`
+	// RoomSubscriptions are individual rooms the client wants full data for,
+	// regardless of whether they fall inside any list window.
+	RoomSubscriptions map[string]SlidingSyncRoomSubscription `json:"room_subscriptions,omitempty"`
+}
+
+// SlidingSyncRequestList describes one named list: the ranges of the sorted
+// room list the client wants filled in, and what to sort/filter by.
+type SlidingSyncRequestList struct {
+	// Ranges are [start, end] indices (inclusive) into the sorted, filtered
+	// room list that the client wants the server to keep populated.
+	Ranges [][2]int `json:"ranges,omitempty"`
+	// Sort is an ordered list of sort operators, most significant first.
+	// Supported values: "by_recency", "by_name", "by_notification_level".
+	Sort []string `json:"sort,omitempty"`
+	// RequiredState is a list of [type, state_key] tuples the client wants
+	// returned for every room in the window. A state_key of "*" means any.
+	RequiredState [][2]string `json:"required_state,omitempty"`
+	// TimelineLimit caps the number of timeline events returned per room.
+	TimelineLimit int `json:"timeline_limit,omitempty"`
+	// Filters narrows down which rooms are eligible for this list at all.
+	Filters *SlidingSyncFilters `json:"filters,omitempty"`
+}
+
+// SlidingSyncFilters restricts which rooms are considered for a list.
+type SlidingSyncFilters struct {
+	IsDM        *bool    `json:"is_dm,omitempty"`
+	IsEncrypted *bool    `json:"is_encrypted,omitempty"`
+	RoomTypes   []string `json:"room_types,omitempty"`
+}
+
+// SlidingSyncRoomSubscription is an explicit, always-returned subscription to
+// a single room, independent of whether it appears in any list's window.
+type SlidingSyncRoomSubscription struct {
+	RequiredState [][2]string `json:"required_state,omitempty"`
+	TimelineLimit int         `json:"timeline_limit,omitempty"`
+}
+
+// SlidingSyncResponse is the response body for a sliding sync request.
+type SlidingSyncResponse struct {
+	// Pos is the server-chosen token the client must echo back as `pos` on
+	// its next request to continue this connection.
+	Pos string `json:"pos"`
+	// Lists maps list key to the ops needed to bring the client's view of
+	// that list up to date.
+	Lists map[string]SlidingSyncResponseList `json:"lists,omitempty"`
+	// Rooms contains per-room data for every room that is in a requested
+	// window or explicitly subscribed to.
+	Rooms map[string]SlidingSyncRoom `json:"rooms,omitempty"`
+}
+
+// SlidingSyncResponseList is the count of rooms matching the list's filters
+// and the ops required to reconcile the client's window with the server's.
+type SlidingSyncResponseList struct {
+	Count int             `json:"count"`
+	Ops   []SlidingSyncOp `json:"ops"`
+}
+
+// SlidingSyncOp is a single list mutation, one of SYNC, INSERT, DELETE or
+// INVALIDATE as defined by MSC3575.
+type SlidingSyncOp struct {
+	Op      string   `json:"op"`
+	Range   [2]int   `json:"range,omitempty"`
+	Index   int      `json:"index,omitempty"`
+	RoomIDs []string `json:"room_ids,omitempty"`
+}
+
+// SlidingSyncRoom is the per-room payload returned for rooms in-window or
+// explicitly subscribed to.
+type SlidingSyncRoom struct {
+	Name          string                          `json:"name,omitempty"`
+	Initial       bool                            `json:"initial,omitempty"`
+	RequiredState []gomatrixserverlib.ClientEvent `json:"required_state,omitempty"`
+	Timeline      []gomatrixserverlib.ClientEvent `json:"timeline,omitempty"`
+	Heroes        []SlidingSyncHero               `json:"heroes,omitempty"`
+}
+
+// SlidingSyncHero is a member used to synthesise a room name when the room
+// has none set, mirroring the `heroes` used for v3 sync room name calculation.
+type SlidingSyncHero struct {
+	UserID      string `json:"user_id"`
+	DisplayName string `json:"display_name,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+}
+
+const (
+	SlidingSyncOpSync       = "SYNC"
+	SlidingSyncOpInsert     = "INSERT"
+	SlidingSyncOpDelete     = "DELETE"
+	SlidingSyncOpInvalidate = "INVALIDATE"
+)